@@ -38,8 +38,9 @@ const (
 	HalfOpen              //熔断半开启
 )
 const (
-	defaultInterval = 1 * time.Second  // 默认的循环间隔
-	defaultTimeout  = 60 * time.Second // 默认的熔断超时时间
+	defaultInterval    = 1 * time.Second  // 默认的循环间隔
+	defaultTimeout     = 60 * time.Second // 默认的熔断超时时间
+	defaultBucketCount = 10               // 默认的滑动窗口子桶数量
 )
 
 var (
@@ -62,21 +63,38 @@ type Options struct {
 	OnStateChange func(name string, before, after State) // 状态切换回调
 	IsSuccessful  func(err error) bool                   // 返回的错误是否代表成功处理
 	Threshold     uint64                                 // 熔断半开启 -> 关闭的请求成功数阈值，并且在半开启状态，请求数不能超过该阈值
+	WindowSize    time.Duration                          // 滑动窗口总时长，>0 时开启滑动窗口，ReadyToTrip 收到的 Counts 中 TotalRequests/TotalSuccess/TotalFailures 为窗口内聚合值，不受 Interval 分代影响；ConsecutiveSuccess/ConsecutiveFailures 仍按分代统计
+	BucketCount   int                                    // 滑动窗口的子桶数量，每个子桶跨度为 WindowSize/BucketCount，默认为 10
+	Clock         Clock                                  // 时间源，默认为 realClock，测试中可替换为可控时钟
+	Classify      func(err error) Result                 // 对执行结果进行分类，未设置时由 IsSuccessful 派生；优先于 IsSuccessful 生效
+	Fallback      func(err error) (interface{}, error)   // Execute 因熔断拒绝（ErrCircuitBreaker/ErrToManyRequests）时调用，返回值替代原本的 nil, err
+	Observer      Observer                               // 可观测性钩子，用于接入 Prometheus/OpenTelemetry 等系统，与 OnStateChange 互不排斥
 }
 
-// TwoStepCircuitBreaker 该熔断器不会接受处理函数，调用者使用 IsAllow 函数获取当前状态，自己执行完业务逻辑后，根据执行结果通知给回调函数变更状态
-type TwoStepCircuitBreaker struct {
-	cb *CircuitBreaker
-}
-
-// CircuitBreaker 普通熔断器，接受一个处理函数，并根据当前状态判断是否真正调用处理函数
-type CircuitBreaker struct {
+// Tracking 熔断器的状态机与计数核心，不依赖 Execute 的函数式调用方式。
+// 调用方通过 Allow 判断当前是否允许执行并拿到对应的分代号，执行完业务逻辑后调用 Report 上报结果。
+// CircuitBreaker.Execute、TwoStepCircuitBreaker.IsAllow 都只是 Tracking 之上的薄封装，
+// 这使得难以用 func() (interface{}, error) 包一层的场景（例如 Redis 客户端、HTTP RoundTripper、gRPC 拦截器）
+// 也能够直接驱动状态机。
+type Tracking struct {
 	opt        *Options
 	counts     *Counts   // 计数信息
 	generation uint64    // 循环的代数
 	expiry     time.Time // 当前周期的过期时间
 	state      State
 	mu         *sync.Mutex
+	window     *slidingWindow // 滑动窗口，WindowSize > 0 时非空
+	timer      Timer          // 开启态下用于在 expiry 到达时自动转为半开启态的定时器，没有新流量也能按时触发
+}
+
+// TwoStepCircuitBreaker 该熔断器不会接受处理函数，调用者使用 IsAllow 函数获取当前状态，自己执行完业务逻辑后，根据执行结果通知给回调函数变更状态
+type TwoStepCircuitBreaker struct {
+	t *Tracking
+}
+
+// CircuitBreaker 普通熔断器，接受一个处理函数，并根据当前状态判断是否真正调用处理函数
+type CircuitBreaker struct {
+	t *Tracking
 }
 
 type Counts struct {
@@ -87,8 +105,8 @@ type Counts struct {
 	ConsecutiveFailures uint64
 }
 
-func NewCircuitBreaker(opt Options) (cb *CircuitBreaker) {
-
+// newTracking 按照 Options 填充默认值后构造 Tracking，NewCircuitBreaker/NewTwoStepCircuitBreaker 共用该逻辑
+func newTracking(opt Options) *Tracking {
 	if opt.Interval <= 0 {
 		opt.Interval = defaultInterval
 	}
@@ -104,8 +122,17 @@ func NewCircuitBreaker(opt Options) (cb *CircuitBreaker) {
 	if opt.IsSuccessful == nil {
 		opt.IsSuccessful = defaultIsSuccessful
 	}
+	if opt.Classify == nil {
+		opt.Classify = classifyFromIsSuccessful(opt.IsSuccessful)
+	}
+	if opt.WindowSize > 0 && opt.BucketCount <= 0 {
+		opt.BucketCount = defaultBucketCount
+	}
+	if opt.Clock == nil {
+		opt.Clock = realClock{}
+	}
 
-	cb = &CircuitBreaker{
+	t := &Tracking{
 		opt:        &opt,
 		counts:     new(Counts),
 		generation: 0,
@@ -113,54 +140,114 @@ func NewCircuitBreaker(opt Options) (cb *CircuitBreaker) {
 		state:      Close,
 		mu:         new(sync.Mutex),
 	}
-	cb.newGeneration(time.Now())
-	return
+	if opt.WindowSize > 0 {
+		t.window = newSlidingWindow(opt.BucketCount, opt.WindowSize, opt.Clock.Now())
+	}
+	t.newGeneration(opt.Clock.Now())
+	return t
+}
+
+// NewTracking 创建一个可由调用方通过 Allow/Report 直接驱动的 Tracking
+func NewTracking(opt Options) *Tracking {
+	return newTracking(opt)
+}
+
+func NewCircuitBreaker(opt Options) (cb *CircuitBreaker) {
+	return &CircuitBreaker{t: newTracking(opt)}
 }
+
 func NewTwoStepCircuitBreaker(opt Options) (cb *TwoStepCircuitBreaker) {
 	return &TwoStepCircuitBreaker{
-		cb: NewCircuitBreaker(opt),
+		t: newTracking(opt),
 	}
 }
 
+// Counts 返回当前计数信息
+func (t *Tracking) Counts() Counts {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return *t.counts
+}
+
 func (c *CircuitBreaker) Counts() Counts {
-	c.mu.Lock()
-	c.mu.Unlock()
-	return *c.counts
+	return c.t.Counts()
 }
 
 func (c *TwoStepCircuitBreaker) Counts() Counts {
-	return c.cb.Counts()
+	return c.t.Counts()
+}
+
+// Allow 判断当前是否允许执行，允许时返回本次请求所处的分代号，供 Report 上报结果时校验
+func (t *Tracking) Allow() (generation uint64, err error) {
+	return t.beforeExecute(t.opt.Clock.Now())
+}
+
+// Report 上报一次由 Allow 放行的请求的执行结果，generation 需要是 Allow 返回的分代号
+func (t *Tracking) Report(generation uint64, success bool) {
+	t.afterExecute(generation, success, t.opt.Clock.Now())
 }
 
 // IsAllow
 func (c *TwoStepCircuitBreaker) IsAllow() (func(success bool), error) {
-	beforeGeneration, err := c.cb.beforeExecute(time.Now())
+	generation, err := c.t.Allow()
 	if err != nil {
 		return nil, err
 	}
+	start := c.t.opt.Clock.Now()
 	return func(success bool) {
-		c.cb.afterExecute(beforeGeneration, success, time.Now())
+		c.t.Report(generation, success)
+		c.t.notifyResult(success, c.t.opt.Clock.Now().Sub(start))
 	}, nil
 
 }
 
-// Execute 执行业务逻辑，如果熔断开启 error 返回 ErrCircuitBreaker,如果熔断半半开启并且请求次数超过半开启阈值返回 ErrToManyRequests
+// Execute 执行业务逻辑，如果熔断开启 error 返回 ErrCircuitBreaker,如果熔断半半开启并且请求次数超过半开启阈值返回 ErrToManyRequests。
+// 设置了 Options.Fallback 时，以上两种拒绝场景改为返回 Fallback(err) 的结果
 func (c *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
-	beforeGeneration, err := c.beforeExecute(time.Now())
+	generation, err := c.t.Allow()
 	if err != nil {
+		if c.t.opt.Fallback != nil {
+			return c.t.opt.Fallback(err)
+		}
 		return nil, err
 	}
+	start := c.t.opt.Clock.Now()
 	defer func() {
 		if p := recover(); p != nil {
-			c.afterExecute(beforeGeneration, c.opt.IsSuccessful(err), time.Now())
+			c.reportResult(generation, err, c.t.opt.Clock.Now().Sub(start))
 			panic(p)
 		}
 	}()
 	resp, err := req()
-	c.afterExecute(beforeGeneration, c.opt.IsSuccessful(err), time.Now())
+	c.reportResult(generation, err, c.t.opt.Clock.Now().Sub(start))
 	return resp, err
 }
 
+// reportResult 按 Options.Classify 对 err 分类后上报给 Tracking 并通知 Observer，Ignore 的结果不计入任何计数
+func (c *CircuitBreaker) reportResult(generation uint64, err error, latency time.Duration) {
+	result := c.t.opt.Classify(err)
+	if result == ResultIgnore {
+		return
+	}
+	success := result == ResultSuccess
+	c.t.Report(generation, success)
+	c.t.notifyResult(success, latency)
+}
+
+// FailureRate 返回熔断器当前的失败率：开启了滑动窗口时返回窗口内失败率，否则返回当前代 Counts 的失败率
+func (t *Tracking) FailureRate() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.window != nil {
+		return t.window.failureRate()
+	}
+	return t.counts.FailureRate()
+}
+
+func (c *CircuitBreaker) FailureRate() float64 {
+	return c.t.FailureRate()
+}
+
 func (c *Counts) clear() {
 	c.TotalRequests = 0
 	c.TotalSuccess = 0
@@ -185,116 +272,152 @@ func (c *Counts) onFailure() {
 }
 
 // setState 设置当前状态、更新循环代数
-func (c *CircuitBreaker) setState(newState State, now time.Time) {
-	if c.state == newState {
+func (t *Tracking) setState(newState State, now time.Time) {
+	if t.state == newState {
 		return
 	}
-	oldState := c.state
-	c.state = newState
+	oldState := t.state
+	t.state = newState
 
-	c.newGeneration(now)
+	t.newGeneration(now)
 
-	if c.opt.OnStateChange != nil {
-		c.opt.OnStateChange(c.opt.Name, oldState, newState)
+	if t.opt.OnStateChange != nil {
+		t.opt.OnStateChange(t.opt.Name, oldState, newState)
+	}
+	if t.opt.Observer != nil {
+		t.opt.Observer.OnStateChange(t.opt.Name, oldState, newState)
 	}
 }
 
 // updateState 更新状态、分代。半开启状态不用更新，关闭状态下会计算新循环代数，开启状态下会计算新代数，并更新状态
-func (c *CircuitBreaker) updateState(now time.Time) {
-	switch c.state {
+func (t *Tracking) updateState(now time.Time) {
+	switch t.state {
 	case Close:
-		if !c.expiry.IsZero() && c.expiry.Before(now) {
-			c.newGeneration(now)
+		if !t.expiry.IsZero() && t.expiry.Before(now) {
+			t.newGeneration(now)
 		}
 	case Open:
-		if c.expiry.Before(now) {
-			c.newGeneration(now)
-			c.setState(HalfOpen, now)
+		if !now.Before(t.expiry) {
+			// setState 在切换到 HalfOpen 后会自行调用 newGeneration，
+			// 这里不需要再调用一次，否则会多触发一次分代(包括一次白白调度又作废的 expiry 定时器)
+			t.setState(HalfOpen, now)
 		}
 	case HalfOpen:
 	}
 }
 
 // newGeneration 进入新一代循环，之前的计数降被清空，并且重新计算下一循环的超时时间
-func (c *CircuitBreaker) newGeneration(now time.Time) uint64 {
-	c.generation++
-	c.counts.clear()
+func (t *Tracking) newGeneration(now time.Time) uint64 {
+	t.generation++
+	t.counts.clear()
 	var zero time.Time
-	switch c.state {
+	switch t.state {
 	case Close:
-		if c.opt.Interval <= 0 {
-			c.expiry = zero
+		if t.opt.Interval <= 0 {
+			t.expiry = zero
 		} else {
-			c.expiry = now.Add(c.opt.Interval)
+			t.expiry = now.Add(t.opt.Interval)
 		}
 	case Open:
-		c.expiry = now.Add(c.opt.Timeout)
+		t.expiry = now.Add(t.opt.Timeout)
+		t.scheduleExpiryTimer(t.opt.Timeout, t.generation)
 	case HalfOpen:
-		c.expiry = zero
+		t.expiry = zero
+	}
+	return t.generation
+}
+
+// scheduleExpiryTimer 在开启态下注册一个到 expiry 时触发的定时器，使状态可以在没有新流量时也准时从开启态转为半开启态。
+// generation 用于在定时器触发时判断该代是否已经因为有新流量提前结束，避免对过期的代重复处理。
+func (t *Tracking) scheduleExpiryTimer(timeout time.Duration, generation uint64) {
+	if t.timer != nil {
+		t.timer.Stop()
 	}
-	return c.generation
+	t.timer = t.opt.Clock.AfterFunc(timeout, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.generation != generation {
+			return
+		}
+		t.updateState(t.opt.Clock.Now())
+	})
 }
 
 // onSuccess 成功情况下的处理，会增加成功计数，并根据当前状态计算下一代或者切换状态
-func (c *CircuitBreaker) onSuccess(now time.Time) {
-	c.counts.onSuccess()
-	switch c.state {
+func (t *Tracking) onSuccess(now time.Time) {
+	t.counts.onSuccess()
+	if t.window != nil {
+		t.window.onSuccess(now)
+		t.syncWindowCounts()
+	}
+	switch t.state {
 	case Close:
-		if c.expiry.Before(now) {
-			c.newGeneration(now)
+		if t.expiry.Before(now) {
+			t.newGeneration(now)
 		}
 	case HalfOpen:
-		if c.counts.ConsecutiveSuccess >= c.opt.Threshold {
-			c.setState(Close, now)
+		if t.counts.ConsecutiveSuccess >= t.opt.Threshold {
+			t.setState(Close, now)
 		}
 	}
 }
 
 // onFailure 失败处理，会根据当前状态切换到新状态
-func (c *CircuitBreaker) onFailure(now time.Time) {
-	switch c.state {
+func (t *Tracking) onFailure(now time.Time) {
+	switch t.state {
 	case Close:
-		c.counts.onFailure()
-		if c.opt.ReadyToTrip(*c.counts) {
-			c.setState(Open, now)
+		t.counts.onFailure()
+		if t.window != nil {
+			t.window.onFailure(now)
+			t.syncWindowCounts()
+		}
+		if t.opt.ReadyToTrip(*t.counts) {
+			t.setState(Open, now)
 		}
 	case HalfOpen:
-		c.setState(Open, now)
+		t.setState(Open, now)
 	}
 }
 
 // beforeExecute 执行前判断，首先更新状态和分代，然后根据最新状态判断是否失败，如果不失败返回当前分代
-func (c *CircuitBreaker) beforeExecute(now time.Time) (uint64, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (t *Tracking) beforeExecute(now time.Time) (uint64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	c.updateState(now)
+	t.updateState(now)
 
-	switch c.state {
+	switch t.state {
 	case Open:
-		return c.generation, ErrCircuitBreaker
+		t.notifyRejected(ErrCircuitBreaker)
+		return t.generation, ErrCircuitBreaker
 	case HalfOpen:
-		if c.counts.TotalRequests >= c.opt.Threshold {
-			return c.generation, ErrToManyRequests
+		if t.counts.TotalRequests >= t.opt.Threshold {
+			t.notifyRejected(ErrToManyRequests)
+			return t.generation, ErrToManyRequests
 		}
 	}
-	c.counts.onRequest()
-	return c.generation, nil
+	t.counts.onRequest()
+	if t.window != nil {
+		t.window.onRequest(now)
+		t.syncWindowCounts()
+	}
+	t.notifyRequest()
+	return t.generation, nil
 }
 
 // afterExecute 执行后计算分代，并且根据执行结果更新状态
-func (c *CircuitBreaker) afterExecute(beforeGeneration uint64, success bool, now time.Time) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (t *Tracking) afterExecute(beforeGeneration uint64, success bool, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	c.updateState(now)
-	if beforeGeneration != c.generation {
+	t.updateState(now)
+	if beforeGeneration != t.generation {
 		return
 	}
 	switch success {
 	case true:
-		c.onSuccess(now)
+		t.onSuccess(now)
 	default:
-		c.onFailure(now)
+		t.onFailure(now)
 	}
 }
@@ -24,8 +24,8 @@ func TestCBGeneration(t *testing.T) {
 	clock := clock.New()
 	set := func(state State) {
 		t.Log("----")
-		cb.setState(state, clock.Now())
-		t.Log(cb.generation)
+		cb.t.setState(state, clock.Now())
+		t.Log(cb.t.generation)
 	}
 
 	set(Close)
@@ -102,37 +102,37 @@ func TestCb(t *testing.T) {
 		Threshold:    2,
 	})
 	assertState := func(state State) {
-		if cb.state != state {
-			t.Fatalf("updateState %s - %s ", cb.state, state)
+		if cb.t.state != state {
+			t.Fatalf("updateState %s - %s ", cb.t.state, state)
 			t.FailNow()
 		}
 	}
 	clock := clock.NewMock()
 
-	cb.onFailure(clock.Now())
-	cb.onFailure(clock.Now())
-	t.Log(cb.state, cb.expiry)
-	_, err := cb.beforeExecute(clock.Now())
+	cb.t.onFailure(clock.Now())
+	cb.t.onFailure(clock.Now())
+	t.Log(cb.t.state, cb.t.expiry)
+	_, err := cb.t.beforeExecute(clock.Now())
 
 	if err != ErrCircuitBreaker {
 		t.Fatalf("updateState -> ErrCircuitBreaker :%s", err)
 		t.FailNow()
 	}
 	clock.Add(timeOut + 1)
-	_, err = cb.beforeExecute(clock.Now())
+	_, err = cb.t.beforeExecute(clock.Now())
 	assertState(HalfOpen)
 
-	cb.onSuccess(clock.Now())
+	cb.t.onSuccess(clock.Now())
 
-	cb.onFailure(clock.Now())
+	cb.t.onFailure(clock.Now())
 	assertState(Open)
 
 	clock.Add(timeOut + 1)
-	cb.updateState(clock.Now())
+	cb.t.updateState(clock.Now())
 	assertState(HalfOpen)
 
-	cb.onSuccess(clock.Now())
-	cb.onSuccess(clock.Now())
+	cb.t.onSuccess(clock.Now())
+	cb.t.onSuccess(clock.Now())
 	assertState(Close)
 }
 
@@ -153,8 +153,8 @@ func TestCB1(t *testing.T) {
 		Threshold:    2,
 	})
 	//assertState := func(state State) {
-	//	if cb.state != state {
-	//		t.Fatalf("updateState %s - %s ", cb.state, state)
+	//	if cb.t.state != state {
+	//		t.Fatalf("updateState %s - %s ", cb.t.state, state)
 	//		t.FailNow()
 	//	}
 	//}
@@ -183,8 +183,8 @@ func TestCB1(t *testing.T) {
 	pseudoSleep(cb, timeOut+1)
 	_, err = cb.Execute(defaultExec)
 	_, err = cb.Execute(defaultExec)
-	if cb.state != Close {
-		t.Fatalf("state != close :%s", cb.state)
+	if cb.t.state != Close {
+		t.Fatalf("state != close :%s", cb.t.state)
 		t.FailNow()
 	}
 
@@ -204,8 +204,8 @@ func TestCB1(t *testing.T) {
 
 }
 func pseudoSleep(cb *CircuitBreaker, period time.Duration) {
-	if !cb.expiry.IsZero() {
-		cb.expiry = cb.expiry.Add(-period)
+	if !cb.t.expiry.IsZero() {
+		cb.t.expiry = cb.t.expiry.Add(-period)
 	}
 }
 
@@ -272,6 +272,268 @@ func TestParallelize(t *testing.T) {
 	wg.Wait()
 }
 
+// TestOpenToHalfOpenGenerationBump 验证 Open -> HalfOpen 只推进一次分代，
+// 而不是 updateState 和 setState 各自调用 newGeneration 导致推进两次
+func TestOpenToHalfOpenGenerationBump(t *testing.T) {
+	now := time.Unix(0, 0)
+	cb := NewCircuitBreaker(Options{
+		Name:     "test",
+		Interval: time.Second,
+		Timeout:  time.Second,
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+	})
+
+	gen, err := cb.t.beforeExecute(now)
+	if err != nil {
+		t.Fatalf("beforeExecute err: %s", err)
+	}
+	cb.t.afterExecute(gen, false, now)
+	if cb.t.state != Open {
+		t.Fatalf("state = %s, want Open", cb.t.state)
+	}
+	before := cb.t.generation
+
+	cb.t.updateState(now.Add(2 * time.Second))
+	if cb.t.state != HalfOpen {
+		t.Fatalf("state = %s, want HalfOpen", cb.t.state)
+	}
+	if got := cb.t.generation - before; got != 1 {
+		t.Fatalf("generation advanced by %d across Open -> HalfOpen, want 1", got)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	mock := clock.NewMock()
+	cb := NewCircuitBreaker(Options{
+		Name:        "test",
+		Interval:    time.Minute, // Interval 设置得很大，确保不会因分代重置计数，验证统计来自滑动窗口而非 Interval
+		Timeout:     time.Second,
+		WindowSize:  10 * time.Second,
+		BucketCount: 10,
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.TotalRequests >= 20 && counts.FailureRate() >= 0.5
+		},
+		Threshold: 1,
+	})
+
+	now := mock.Now()
+	ok := func(success bool) {
+		gen, err := cb.t.beforeExecute(now)
+		if err != nil {
+			t.Fatalf("beforeExecute err: %s", err)
+		}
+		cb.t.afterExecute(gen, success, now)
+	}
+	for i := 0; i < 19; i++ {
+		ok(i%2 == 0) // 10 次成功，9 次失败，此时请求总数不足 20，不应触发熔断
+	}
+	if cb.t.state != Close {
+		t.Fatalf("state != Close before 20 requests: %s", cb.t.state)
+	}
+
+	ok(false) // 第 20 次请求为失败，失败率达到 10/20=0.5，触发熔断
+	if cb.t.state != Open {
+		t.Fatalf("state != Open after failure rate exceeded: %s", cb.t.state)
+	}
+	if rate := cb.FailureRate(); rate != 0.5 {
+		t.Fatalf("FailureRate() = %f, want 0.5", rate)
+	}
+}
+
+// TestSlidingWindowEviction 推进时钟跨越多个子桶的时间跨度，验证滑出窗口范围的子桶被清空，
+// 并且单次跨度超过整个窗口之后 headTime 不会永久落后于真实时间
+func TestSlidingWindowEviction(t *testing.T) {
+	start := time.Unix(0, 0)
+	w := newSlidingWindow(10, 10*time.Second, start)
+
+	w.onRequest(start)
+	w.onSuccess(start)
+	if requests, _, _ := w.sum(); requests != 1 {
+		t.Fatalf("requests = %d, want 1", requests)
+	}
+
+	// 推进到窗口范围之外，验证过期子桶被清空
+	w.advance(start.Add(10 * time.Second))
+	if requests, _, _ := w.sum(); requests != 0 {
+		t.Fatalf("requests after eviction = %d, want 0", requests)
+	}
+
+	// 单次间隔远超过整个窗口之后，headTime 应对齐到当前时间，
+	// 而不是永久落后于真实时间——否则之后哪怕请求间隔远小于窗口，
+	// advance 也会一直把 elapsed 算成远超窗口的值，每次都清空整环
+	gapEnd := start.Add(1000 * time.Second)
+	w.advance(gapEnd)
+	now := gapEnd
+	for i := 0; i < 5; i++ {
+		now = now.Add(200 * time.Millisecond)
+		w.onRequest(now)
+	}
+	if requests, _, _ := w.sum(); requests != 5 {
+		t.Fatalf("requests after gap = %d, want 5 (headTime desynced from real time)", requests)
+	}
+}
+
+// mockClock 把 github.com/benbjohnson/clock 的可控时钟适配为本包的 Clock 接口，
+// 使依赖 Clock 的行为（包括到 expiry 时自动触发的定时器）可以在测试里确定性地推进，
+// 而不需要像 pseudoSleep 那样直接篡改内部字段
+type mockClock struct {
+	*clock.Mock
+}
+
+func (m mockClock) AfterFunc(d time.Duration, f func()) Timer {
+	return m.Mock.AfterFunc(d, f)
+}
+
+func TestClockInjection(t *testing.T) {
+	mock := mockClock{clock.NewMock()}
+	stateChanged := make(chan State, 2)
+	cb := NewCircuitBreaker(Options{
+		Name:     "test",
+		Interval: time.Second,
+		Timeout:  2 * time.Second,
+		Clock:    mock,
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+		OnStateChange: func(name string, before, after State) {
+			stateChanged <- after
+		},
+	})
+
+	cb.Execute(func() (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	if got := <-stateChanged; got != Open {
+		t.Fatalf("state = %s, want Open", got)
+	}
+
+	// 没有任何新流量，仅靠 Clock.AfterFunc 在 expiry 到达时自动把状态从开启态转为半开启态。
+	// AfterFunc 的回调在独立 goroutine 中执行，mock.Add 本身不等待其完成，因此这里等待 channel 而不是立即断言
+	mock.Add(2*time.Second + time.Millisecond)
+	select {
+	case got := <-stateChanged:
+		if got != HalfOpen {
+			t.Fatalf("state = %s, want HalfOpen", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Open -> HalfOpen transition")
+	}
+}
+
+func TestFallback(t *testing.T) {
+	cb := NewCircuitBreaker(Options{
+		Name:     "test",
+		Interval: time.Second,
+		Timeout:  time.Minute,
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+		Fallback: func(err error) (interface{}, error) {
+			return "fallback", nil
+		},
+	})
+
+	cb.Execute(func() (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+
+	resp, err := cb.Execute(func() (interface{}, error) {
+		t.Fatalf("req should not be called once circuit is open")
+		return nil, nil
+	})
+	if err != nil || resp != "fallback" {
+		t.Fatalf("Execute() = %v, %v ; want fallback, nil", resp, err)
+	}
+}
+
+var errIgnored = errors.New("ignored")
+
+func TestClassifyIgnore(t *testing.T) {
+	cb := NewCircuitBreaker(Options{
+		Name:     "test",
+		Interval: time.Second,
+		Timeout:  time.Second,
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+		Classify: func(err error) Result {
+			if err == errIgnored {
+				return ResultIgnore
+			}
+			if err != nil {
+				return ResultFailure
+			}
+			return ResultSuccess
+		},
+	})
+
+	for i := 0; i < 5; i++ {
+		// Execute 原样返回业务函数的 error，Classify 只影响是否计入熔断统计
+		_, err := cb.Execute(func() (interface{}, error) {
+			return nil, errIgnored
+		})
+		if err != errIgnored {
+			t.Fatalf("Execute() err = %v, want errIgnored", err)
+		}
+	}
+	if cb.t.state != Close {
+		t.Fatalf("state != Close after ignored errors: %s", cb.t.state)
+	}
+	if counts := cb.Counts(); counts.TotalFailures != 0 {
+		t.Fatalf("TotalFailures = %d, want 0", counts.TotalFailures)
+	}
+}
+
+// fakeObserver 记录 Observer 回调被调用的次数，用于测试 Observer 钩子是否按预期触发
+type fakeObserver struct {
+	requests     int
+	results      int
+	rejected     int
+	stateChanges int
+}
+
+func (f *fakeObserver) OnRequest(name string, state State) { f.requests++ }
+func (f *fakeObserver) OnResult(name string, success bool, latency time.Duration) {
+	f.results++
+}
+func (f *fakeObserver) OnRejected(name string, err error)              { f.rejected++ }
+func (f *fakeObserver) OnStateChange(name string, before, after State) { f.stateChanges++ }
+
+func TestObserver(t *testing.T) {
+	observer := &fakeObserver{}
+	cb := NewCircuitBreaker(Options{
+		Name:     "test",
+		Interval: time.Second,
+		Timeout:  time.Minute,
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+		Observer: observer,
+	})
+
+	cb.Execute(func() (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	cb.Execute(func() (interface{}, error) {
+		return nil, nil
+	})
+
+	if observer.requests != 1 {
+		t.Fatalf("requests = %d, want 1", observer.requests)
+	}
+	if observer.results != 1 {
+		t.Fatalf("results = %d, want 1", observer.results)
+	}
+	if observer.rejected != 1 {
+		t.Fatalf("rejected = %d, want 1", observer.rejected)
+	}
+	if observer.stateChanges != 1 {
+		t.Fatalf("stateChanges = %d, want 1", observer.stateChanges)
+	}
+}
+
 func TestTwoStep(t *testing.T) {
 	cb := NewTwoStepCircuitBreaker(Options{
 		Name:     "test",
@@ -0,0 +1,81 @@
+// Package cbprom 将 circuit_breaker.Observer 接入 Prometheus，
+// 注册 requests_total/results_total/rejections_total/state_transitions_total 计数器以及当前状态的 Gauge
+package cbprom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cb "github.com/fuyao-w/circuit_breaker"
+)
+
+// Metrics 实现 cb.Observer，把熔断器的请求、拒绝、结果、状态切换事件上报为 Prometheus 指标，
+// name 取自 Options.Name，作为所有指标的公共 label。
+// requestsTotal、rejectionsTotal、resultsTotal 是三个独立的计数器，分别对应"放行的请求"、
+// "被拒绝的请求"、"执行完毕的结果"三类互不重叠的事件，避免同一次请求被重复计入
+type Metrics struct {
+	requestsTotal    *prometheus.CounterVec
+	rejectionsTotal  *prometheus.CounterVec
+	resultsTotal     *prometheus.CounterVec
+	stateTransitions *prometheus.CounterVec
+	state            *prometheus.GaugeVec
+}
+
+var _ cb.Observer = (*Metrics)(nil)
+
+// NewMetrics 创建一组指标并注册到 reg，reg 为 nil 时注册到 prometheus.DefaultRegisterer
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "circuit_breaker_requests_total",
+			Help: "Total number of requests let through by the circuit breaker, labeled by the state at admission time",
+		}, []string{"name", "state"}),
+		rejectionsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "circuit_breaker_rejections_total",
+			Help: "Total number of requests rejected by the circuit breaker, labeled by reason",
+		}, []string{"name", "reason"}),
+		resultsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "circuit_breaker_results_total",
+			Help: "Total number of completed requests, labeled by outcome",
+		}, []string{"name", "outcome"}),
+		stateTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "circuit_breaker_state_transitions_total",
+			Help: "Total number of state transitions, labeled by the resulting state",
+		}, []string{"name", "state"}),
+		state: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Current state of the circuit breaker (0=Close, 1=Open, 2=HalfOpen)",
+		}, []string{"name"}),
+	}
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	reg.MustRegister(m.requestsTotal, m.rejectionsTotal, m.resultsTotal, m.stateTransitions, m.state)
+	return m
+}
+
+// OnRequest 在请求被放行时调用
+func (m *Metrics) OnRequest(name string, state cb.State) {
+	m.requestsTotal.WithLabelValues(name, state.String()).Inc()
+}
+
+// OnResult 在请求执行完毕后调用，latency 未被使用到计数器中，预留给接入 Histogram 的场景
+func (m *Metrics) OnResult(name string, success bool, latency time.Duration) {
+	outcome := "failure"
+	if success {
+		outcome = "success"
+	}
+	m.resultsTotal.WithLabelValues(name, outcome).Inc()
+}
+
+// OnRejected 在请求被熔断拒绝时调用
+func (m *Metrics) OnRejected(name string, err error) {
+	m.rejectionsTotal.WithLabelValues(name, err.Error()).Inc()
+}
+
+// OnStateChange 在状态切换时调用
+func (m *Metrics) OnStateChange(name string, before, after cb.State) {
+	m.stateTransitions.WithLabelValues(name, after.String()).Inc()
+	m.state.WithLabelValues(name).Set(float64(after))
+}
@@ -0,0 +1,52 @@
+package cbprom
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	cb "github.com/fuyao-w/circuit_breaker"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewMetrics(reg)
+
+	m.OnRequest("test", cb.Close)
+	m.OnResult("test", true, time.Millisecond)
+	m.OnResult("test", false, time.Millisecond)
+	m.OnRejected("test", cb.ErrCircuitBreaker)
+	m.OnStateChange("test", cb.Close, cb.Open)
+
+	if got := testutil.ToFloat64(m.requestsTotal.WithLabelValues("test", cb.Close.String())); got != 1 {
+		t.Fatalf("requests_total = %f, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.resultsTotal.WithLabelValues("test", "success")); got != 1 {
+		t.Fatalf("results_total{success} = %f, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.resultsTotal.WithLabelValues("test", "failure")); got != 1 {
+		t.Fatalf("results_total{failure} = %f, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.rejectionsTotal.WithLabelValues("test", cb.ErrCircuitBreaker.Error())); got != 1 {
+		t.Fatalf("rejections_total = %f, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.state.WithLabelValues("test")); got != float64(cb.Open) {
+		t.Fatalf("state = %f, want %d", got, cb.Open)
+	}
+
+	// requests_total 和 results_total 是两个独立的计数器，即便都只被触发了一次，
+	// 加总起来也不应让同一次请求被重复计入某一个指标
+	if got := testutil.ToFloat64(m.requestsTotal.WithLabelValues("test", cb.Close.String())); got != 1 {
+		t.Fatalf("requests_total double counted: = %f, want 1", got)
+	}
+
+	if err := errors.New("boom"); err != nil {
+		m.OnRejected("test", err)
+		if got := testutil.ToFloat64(m.rejectionsTotal.WithLabelValues("test", "boom")); got != 1 {
+			t.Fatalf("rejections_total{boom} = %f, want 1", got)
+		}
+	}
+}
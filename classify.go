@@ -0,0 +1,32 @@
+package circuit_breaker
+
+// Result 是 Classify 对一次执行结果的分类
+type Result int
+
+const (
+	ResultSuccess Result = iota // 视为成功，计入成功计数
+	ResultFailure               // 视为失败，计入失败计数，可能触发 ReadyToTrip
+	ResultIgnore                // 忽略本次结果，不计入任何计数，例如 context.Canceled、参数校验错误等客户端自身的错误
+)
+
+func (r Result) String() string {
+	switch r {
+	case ResultSuccess:
+		return "Success"
+	case ResultFailure:
+		return "Failure"
+	case ResultIgnore:
+		return "Ignore"
+	}
+	return "Unknown"
+}
+
+// classifyFromIsSuccessful 在 Classify 未设置时，用旧版 IsSuccessful 派生出等价的 Classify，保持向后兼容
+func classifyFromIsSuccessful(isSuccessful func(err error) bool) func(err error) Result {
+	return func(err error) Result {
+		if isSuccessful(err) {
+			return ResultSuccess
+		}
+		return ResultFailure
+	}
+}
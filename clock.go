@@ -0,0 +1,27 @@
+package circuit_breaker
+
+import "time"
+
+// Timer 代表一个延时任务的句柄，Stop 用于在任务触发前取消它
+type Timer interface {
+	Stop() bool
+}
+
+// Clock 抽象了熔断器依赖的时间相关操作，默认使用 realClock 包装标准库 time 包。
+// 测试中可以替换为可控时钟（例如 github.com/benbjohnson/clock），从而无需像 pseudoSleep 那样直接篡改内部字段；
+// AfterFunc 还使得 Open -> HalfOpen 这类状态转移可以在没有新流量时也准时触发。
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// realClock 是 Clock 的默认实现，直接转发到标准库 time 包
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
@@ -0,0 +1,84 @@
+package circuit_breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// groupEntry 记录一个被 Group 缓存的熔断器以及它最近一次被访问的时间，用于空闲淘汰
+type groupEntry struct {
+	cb         *CircuitBreaker
+	lastAccess time.Time
+}
+
+// Group 按 key（例如下游服务名、endpoint、分片）懒加载并缓存一组 *CircuitBreaker，
+// 避免调用方手动维护 map[string]*CircuitBreaker 来管理一批下游的熔断状态
+type Group struct {
+	mu      sync.Mutex
+	newOpt  func(name string) Options
+	entries map[string]*groupEntry
+
+	// IdleTimeout 为 0 表示不淘汰；否则超过该时长未被访问的熔断器会在下次 Get/Execute 时被懒淘汰
+	IdleTimeout time.Duration
+}
+
+// NewGroup 创建一个 Group，newOpt 用于在某个 key 第一次被访问时构造对应的 Options
+func NewGroup(newOpt func(name string) Options) *Group {
+	return &Group{
+		newOpt:  newOpt,
+		entries: make(map[string]*groupEntry),
+	}
+}
+
+// Get 返回 key 对应的熔断器，不存在则通过 newOpt 创建
+func (g *Group) Get(key string) *CircuitBreaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.getOrCreate(key, time.Now())
+}
+
+// Execute 获取 key 对应的熔断器后执行业务逻辑，等价于 g.Get(key).Execute(req)
+func (g *Group) Execute(key string, req func() (interface{}, error)) (interface{}, error) {
+	return g.Get(key).Execute(req)
+}
+
+// Remove 移除 key 对应的熔断器，之后再次访问该 key 会重新创建
+func (g *Group) Remove(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.entries, key)
+}
+
+// ForEach 遍历当前缓存的所有熔断器，name 为对应的 key
+func (g *Group) ForEach(fn func(name string, cb *CircuitBreaker)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for name, entry := range g.entries {
+		fn(name, entry.cb)
+	}
+}
+
+// getOrCreate 在持有 g.mu 的前提下返回 key 对应的熔断器，并顺带淘汰空闲过久的其它熔断器
+func (g *Group) getOrCreate(key string, now time.Time) *CircuitBreaker {
+	g.evictIdle(now)
+
+	if entry, ok := g.entries[key]; ok {
+		entry.lastAccess = now
+		return entry.cb
+	}
+	cb := NewCircuitBreaker(g.newOpt(key))
+	g.entries[key] = &groupEntry{cb: cb, lastAccess: now}
+	return cb
+}
+
+// evictIdle 清理超过 IdleTimeout 未被访问的熔断器，IdleTimeout <= 0 时不做任何事
+func (g *Group) evictIdle(now time.Time) {
+	if g.IdleTimeout <= 0 {
+		return
+	}
+	for key, entry := range g.entries {
+		if now.Sub(entry.lastAccess) > g.IdleTimeout {
+			delete(g.entries, key)
+		}
+	}
+}
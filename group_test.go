@@ -0,0 +1,82 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+	g := NewGroup(func(name string) Options {
+		return Options{
+			Name:     name,
+			Interval: time.Second,
+			Timeout:  time.Second,
+			ReadyToTrip: func(counts Counts) bool {
+				return counts.ConsecutiveFailures >= 2
+			},
+		}
+	})
+
+	cbA := g.Get("a")
+	cbA2 := g.Get("a")
+	if cbA != cbA2 {
+		t.Fatalf("Get(\"a\") returned two different breakers")
+	}
+
+	cbB := g.Get("b")
+	if cbA == cbB {
+		t.Fatalf("Get(\"a\") and Get(\"b\") returned the same breaker")
+	}
+
+	names := map[string]bool{}
+	g.ForEach(func(name string, cb *CircuitBreaker) {
+		names[name] = true
+	})
+	if !names["a"] || !names["b"] || len(names) != 2 {
+		t.Fatalf("ForEach saw %v, want a and b only", names)
+	}
+
+	g.Remove("a")
+	if g.Get("a") == cbA {
+		t.Fatalf("Get(\"a\") after Remove should create a new breaker")
+	}
+}
+
+func TestGroupIdleEviction(t *testing.T) {
+	g := NewGroup(func(name string) Options {
+		return Options{Name: name}
+	})
+	g.IdleTimeout = time.Minute
+
+	now := time.Now()
+	cb := g.getOrCreate("a", now)
+	if g.getOrCreate("a", now.Add(time.Minute+time.Second)) == cb {
+		t.Fatalf("getOrCreate should recreate a breaker that has been idle past IdleTimeout")
+	}
+}
+
+func TestGroupExecute(t *testing.T) {
+	g := NewGroup(func(name string) Options {
+		return Options{
+			Name: name,
+			ReadyToTrip: func(counts Counts) bool {
+				return counts.ConsecutiveFailures >= 1
+			},
+		}
+	})
+
+	_, err := g.Execute("svc", func() (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	if err == nil {
+		t.Fatalf("Execute err = nil, want boom")
+	}
+
+	_, err = g.Execute("svc", func() (interface{}, error) {
+		return nil, nil
+	})
+	if err != ErrCircuitBreaker {
+		t.Fatalf("Execute err = %v, want ErrCircuitBreaker", err)
+	}
+}
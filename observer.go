@@ -0,0 +1,34 @@
+package circuit_breaker
+
+import "time"
+
+// Observer 暴露熔断器运行时的各类信号，用于接入 Prometheus/OpenTelemetry 等可观测性系统。
+// OnStateChange 与 Options.OnStateChange 作用相同，两者都设置时会被依次调用；
+// 其余三个回调弥补了过去只有状态切换一个信号、无法支撑生产看板的问题
+type Observer interface {
+	OnRequest(name string, state State)                        // 请求被放行、即将执行时调用，state 为放行时所处的状态
+	OnResult(name string, success bool, latency time.Duration) // 请求执行完毕后调用，success 为 Classify 之后的结果
+	OnRejected(name string, err error)                         // 请求被熔断拒绝时调用，err 为 ErrCircuitBreaker 或 ErrToManyRequests
+	OnStateChange(name string, before, after State)            // 状态切换时调用
+}
+
+// notifyRequest 在请求被放行时通知 Observer
+func (t *Tracking) notifyRequest() {
+	if t.opt.Observer != nil {
+		t.opt.Observer.OnRequest(t.opt.Name, t.state)
+	}
+}
+
+// notifyRejected 在请求被熔断拒绝时通知 Observer
+func (t *Tracking) notifyRejected(err error) {
+	if t.opt.Observer != nil {
+		t.opt.Observer.OnRejected(t.opt.Name, err)
+	}
+}
+
+// notifyResult 在请求执行完毕、结果上报给 Tracking 的同时通知 Observer
+func (t *Tracking) notifyResult(success bool, latency time.Duration) {
+	if t.opt.Observer != nil {
+		t.opt.Observer.OnResult(t.opt.Name, success, latency)
+	}
+}
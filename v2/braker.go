@@ -0,0 +1,85 @@
+package v2
+
+import (
+	"errors"
+
+	cb "github.com/fuyao-w/circuit_breaker"
+)
+
+// ErrUnexpectedType 在 Fallback 返回的值无法断言为 T 时返回，
+// 常见于多个类型参数不同的 CircuitBreaker[T] 共用了同一个 Options（从而共用了同一个 Fallback）
+var ErrUnexpectedType = errors.New("v2: fallback result does not match T")
+
+/*
+	circuit_breaker 的泛型封装
+
+	CircuitBreaker[T]/TwoStepCircuitBreaker[T] 在旧版 *cb.CircuitBreaker 之上包了一层泛型外壳，
+	Execute 直接返回业务函数声明的目标类型 T，调用方不再需要在每个调用点做 interface{} 类型断言。
+	状态机、计数、滑动窗口等核心逻辑仍然由旧版 cb.CircuitBreaker 负责，本包只负责类型转换。
+*/
+
+// CircuitBreaker 泛型熔断器，T 为业务处理函数的返回值类型
+type CircuitBreaker[T any] struct {
+	cb *cb.CircuitBreaker
+}
+
+// CircuitBreakerAny 等价于 CircuitBreaker[any]，用于兼容旧版 interface{} 返回值的调用方
+type CircuitBreakerAny = CircuitBreaker[any]
+
+// NewCircuitBreaker 创建一个泛型熔断器，Options 与旧版完全一致
+func NewCircuitBreaker[T any](opt cb.Options) *CircuitBreaker[T] {
+	return &CircuitBreaker[T]{cb: cb.NewCircuitBreaker(opt)}
+}
+
+// Execute 执行业务逻辑，直接返回 T 类型的结果，无需调用方做类型断言
+func (c *CircuitBreaker[T]) Execute(req func() (T, error)) (T, error) {
+	resp, err := c.cb.Execute(func() (interface{}, error) {
+		return req()
+	})
+	if resp == nil {
+		var zero T
+		return zero, err
+	}
+	v, ok := resp.(T)
+	if !ok {
+		var zero T
+		if err != nil {
+			return zero, err
+		}
+		return zero, ErrUnexpectedType
+	}
+	return v, err
+}
+
+// Counts 返回当前计数信息
+func (c *CircuitBreaker[T]) Counts() cb.Counts {
+	return c.cb.Counts()
+}
+
+// FailureRate 返回当前失败率
+func (c *CircuitBreaker[T]) FailureRate() float64 {
+	return c.cb.FailureRate()
+}
+
+// TwoStepCircuitBreaker 泛型版本的二段式熔断器，调用者使用 IsAllow 获取当前状态，自行执行业务逻辑后上报结果
+type TwoStepCircuitBreaker[T any] struct {
+	cb *cb.TwoStepCircuitBreaker
+}
+
+// TwoStepCircuitBreakerAny 等价于 TwoStepCircuitBreaker[any]，用于兼容旧版调用方
+type TwoStepCircuitBreakerAny = TwoStepCircuitBreaker[any]
+
+// NewTwoStepCircuitBreaker 创建一个泛型二段式熔断器
+func NewTwoStepCircuitBreaker[T any](opt cb.Options) *TwoStepCircuitBreaker[T] {
+	return &TwoStepCircuitBreaker[T]{cb: cb.NewTwoStepCircuitBreaker(opt)}
+}
+
+// IsAllow 判断当前是否允许执行，返回的回调函数用于上报业务执行结果
+func (c *TwoStepCircuitBreaker[T]) IsAllow() (func(success bool), error) {
+	return c.cb.IsAllow()
+}
+
+// Counts 返回当前计数信息
+func (c *TwoStepCircuitBreaker[T]) Counts() cb.Counts {
+	return c.cb.Counts()
+}
@@ -0,0 +1,99 @@
+package v2
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	cb "github.com/fuyao-w/circuit_breaker"
+)
+
+func TestTypedExecute(t *testing.T) {
+	breaker := NewCircuitBreaker[int](cb.Options{
+		Name:     "test",
+		Interval: time.Second,
+		Timeout:  time.Second,
+		ReadyToTrip: func(counts cb.Counts) bool {
+			return counts.ConsecutiveFailures >= 2
+		},
+	})
+
+	resp, err := breaker.Execute(func() (int, error) {
+		return 42, nil
+	})
+	if err != nil || resp != 42 {
+		t.Fatalf("Execute() = %d, %v ; want 42, nil", resp, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		breaker.Execute(func() (int, error) {
+			return 0, errors.New("boom")
+		})
+	}
+
+	resp, err = breaker.Execute(func() (int, error) {
+		return 42, nil
+	})
+	if err != cb.ErrCircuitBreaker {
+		t.Fatalf("err = %v, want ErrCircuitBreaker", err)
+	}
+	if resp != 0 {
+		t.Fatalf("resp = %d, want zero value", resp)
+	}
+}
+
+func TestTypedExecuteFallback(t *testing.T) {
+	breaker := NewCircuitBreaker[int](cb.Options{
+		Name:     "test",
+		Interval: time.Second,
+		Timeout:  time.Second,
+		ReadyToTrip: func(counts cb.Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+		Fallback: func(err error) (interface{}, error) {
+			return 7, nil
+		},
+	})
+
+	breaker.Execute(func() (int, error) {
+		return 0, errors.New("boom")
+	})
+
+	resp, err := breaker.Execute(func() (int, error) {
+		return 42, nil
+	})
+	if err != nil || resp != 7 {
+		t.Fatalf("Execute() = %d, %v ; want 7, nil", resp, err)
+	}
+}
+
+// TestTypedExecuteFallbackTypeMismatch 验证 Fallback 返回了与 T 不匹配的类型时，
+// Execute 返回 ErrUnexpectedType 而不是 panic——同一个 Options（以及它的 Fallback）
+// 有可能被不同类型参数的 CircuitBreaker[T] 共用
+func TestTypedExecuteFallbackTypeMismatch(t *testing.T) {
+	breaker := NewCircuitBreaker[int](cb.Options{
+		Name:     "test",
+		Interval: time.Second,
+		Timeout:  time.Second,
+		ReadyToTrip: func(counts cb.Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+		Fallback: func(err error) (interface{}, error) {
+			return "not an int", nil
+		},
+	})
+
+	breaker.Execute(func() (int, error) {
+		return 0, errors.New("boom")
+	})
+
+	resp, err := breaker.Execute(func() (int, error) {
+		return 42, nil
+	})
+	if err != ErrUnexpectedType {
+		t.Fatalf("err = %v, want ErrUnexpectedType", err)
+	}
+	if resp != 0 {
+		t.Fatalf("resp = %d, want zero value", resp)
+	}
+}
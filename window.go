@@ -0,0 +1,107 @@
+package circuit_breaker
+
+import "time"
+
+// windowBucket 滑动窗口的单个子桶，统计该子桶时间跨度内的请求情况
+type windowBucket struct {
+	requests  uint64
+	successes uint64
+	failures  uint64
+}
+
+// slidingWindow 基于环形缓冲区实现的滑动窗口，用于在 WindowSize 范围内按 BucketCount 个子桶滚动统计请求情况，
+// 不受 Options.Interval 分代机制影响，使 ReadyToTrip 可以基于"最近一段时间内的失败率"做出决策
+type slidingWindow struct {
+	buckets    []windowBucket
+	bucketSpan time.Duration // 每个子桶覆盖的时间跨度，等于 WindowSize/BucketCount
+	headTime   time.Time     // 当前子桶(head)起始时间
+	head       int           // 当前子桶下标
+}
+
+// newSlidingWindow 创建一个滑动窗口，bucketCount 个子桶平分 windowSize 的时间跨度
+func newSlidingWindow(bucketCount int, windowSize time.Duration, now time.Time) *slidingWindow {
+	return &slidingWindow{
+		buckets:    make([]windowBucket, bucketCount),
+		bucketSpan: windowSize / time.Duration(bucketCount),
+		headTime:   now,
+		head:       0,
+	}
+}
+
+// advance 根据当前时间推进窗口，清空已经滑出窗口范围的子桶
+func (w *slidingWindow) advance(now time.Time) {
+	elapsed := now.Sub(w.headTime)
+	if elapsed < w.bucketSpan {
+		return
+	}
+	steps := int(elapsed / w.bucketSpan)
+	if steps > len(w.buckets) {
+		// 跨度已经超过整个窗口，所有子桶都已过期：直接清空整环，
+		// 并把 headTime 对齐到 now 所在子桶的起始时刻，使之后的 elapsed 从真实时间重新计算，
+		// 不会在每次调用时都得到超过整个窗口的值
+		for i := range w.buckets {
+			w.buckets[i] = windowBucket{}
+		}
+		w.headTime = now.Add(-(elapsed % w.bucketSpan))
+		return
+	}
+	for i := 0; i < steps; i++ {
+		w.head = (w.head + 1) % len(w.buckets)
+		w.buckets[w.head] = windowBucket{}
+	}
+	w.headTime = w.headTime.Add(time.Duration(steps) * w.bucketSpan)
+}
+
+func (w *slidingWindow) onRequest(now time.Time) {
+	w.advance(now)
+	w.buckets[w.head].requests++
+}
+
+func (w *slidingWindow) onSuccess(now time.Time) {
+	w.advance(now)
+	w.buckets[w.head].successes++
+}
+
+func (w *slidingWindow) onFailure(now time.Time) {
+	w.advance(now)
+	w.buckets[w.head].failures++
+}
+
+// sum 汇总窗口内所有存活子桶的计数
+func (w *slidingWindow) sum() (requests, successes, failures uint64) {
+	for _, b := range w.buckets {
+		requests += b.requests
+		successes += b.successes
+		failures += b.failures
+	}
+	return
+}
+
+// failureRate 返回窗口内的失败率，没有请求时返回 0
+func (w *slidingWindow) failureRate() float64 {
+	requests, _, failures := w.sum()
+	if requests == 0 {
+		return 0
+	}
+	return float64(failures) / float64(requests)
+}
+
+// FailureRate 返回 Counts 中的失败率，没有请求时返回 0
+func (c Counts) FailureRate() float64 {
+	if c.TotalRequests == 0 {
+		return 0
+	}
+	return float64(c.TotalFailures) / float64(c.TotalRequests)
+}
+
+// syncWindowCounts 将滑动窗口内聚合的计数同步到 t.counts，使 ReadyToTrip 收到窗口范围内的聚合 Counts。
+// 只在关闭态同步：半开启态的 TotalRequests 用于和 Threshold 比较，需保持为本代的请求数
+func (t *Tracking) syncWindowCounts() {
+	if t.state != Close {
+		return
+	}
+	requests, successes, failures := t.window.sum()
+	t.counts.TotalRequests = requests
+	t.counts.TotalSuccess = successes
+	t.counts.TotalFailures = failures
+}